@@ -2,6 +2,7 @@ package main
 
 import (
 	"archive/tar"
+	"bytes"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
@@ -13,18 +14,24 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/klauspost/pgzip"
+	"github.com/maestroi/snapshot-service/internal/archive/encrypt"
+	"github.com/maestroi/snapshot-service/internal/chainprobe"
+	"github.com/maestroi/snapshot-service/internal/notify"
+	"github.com/maestroi/snapshot-service/internal/storage"
+	"github.com/maestroi/snapshot-service/internal/storage/azure"
+	"github.com/maestroi/snapshot-service/internal/storage/local"
+	storages3 "github.com/maestroi/snapshot-service/internal/storage/s3"
+	"github.com/maestroi/snapshot-service/internal/storage/ssh"
+	"github.com/maestroi/snapshot-service/internal/storage/webdav"
 	"github.com/robfig/cron/v3"
 	"golang.org/x/net/context"
 )
@@ -34,20 +41,155 @@ const (
 )
 
 type Config struct {
-	ContainerNames  []string `json:"container_names"`
-	Network         string   `json:"network"`
-	Protocol        string   `json:"protocol"`
-	ProtocolHistory string   `json:"protocol_history"`
-	ProtocolVer     string   `json:"protocol_version"`
-	IgnoreFiles     []string `json:"ignore_files"`
-	CrontTime       string   `json:"cron_time"`
-	FilePath        string   `json:"file_path"`
-	BucketName      string   `json:"bucket_name"`
-	AccessKey       string   `json:"access_key"`
-	SecretKey       string   `json:"secret_key"`
-	Endpoint        string   `json:"endpoint"`
-	Region          string   `json:"region"`
-	SnapshotToKeep  int      `json:"snapshot_to_keep"`
+	ContainerNames  []string        `json:"container_names"`
+	Network         string          `json:"network"`
+	Protocol        string          `json:"protocol"`
+	ProtocolHistory string          `json:"protocol_history"`
+	ProtocolVer     string          `json:"protocol_version"`
+	IgnoreFiles     []string        `json:"ignore_files"`
+	CrontTime       string          `json:"cron_time"`
+	FilePath        string          `json:"file_path"`
+	SnapshotToKeep  int             `json:"snapshot_to_keep"`
+	Backends        []BackendConfig `json:"backends"`
+
+	// Deprecated: set Backends with a single "s3" entry instead. Kept so
+	// existing configs without a "backends" section keep working.
+	BucketName string `json:"bucket_name"`
+	AccessKey  string `json:"access_key"`
+	SecretKey  string `json:"secret_key"`
+	Endpoint   string `json:"endpoint"`
+	Region     string `json:"region"`
+
+	// Passphrase and AgeRecipients configure archive encryption. At most one
+	// should be set; if neither is set, archives are uploaded unencrypted.
+	// AgeIdentityPath points at an age identity file holding the private
+	// key(s) matching AgeRecipients, and is required to restore or decrypt
+	// an archive that was encrypted that way.
+	Passphrase      string   `json:"passphrase"`
+	AgeRecipients   []string `json:"age_recipients"`
+	AgeIdentityPath string   `json:"age_identity_path"`
+
+	// Notifications lists shoutrrr URLs (Slack, Discord, Telegram, a generic
+	// webhook, SMTP, ...) to notify once a backup run has restarted its
+	// containers. A run that returns earlier (storage or encryption setup
+	// fails, or a container cannot be stopped or started) sends no
+	// notification. NotificationTemplate
+	// optionally points at a text/template file to render instead of the
+	// built-in success/failure templates.
+	Notifications        []string `json:"notifications"`
+	NotificationTemplate string   `json:"notification_template"`
+
+	// ChainProbeContainer names the container queried for the current block
+	// height/hash before it is stopped, via the chainprobe implementation
+	// matching Protocol. Defaults to the first entry of ContainerNames.
+	ChainProbeContainer string `json:"chain_probe_container"`
+}
+
+// BackendConfig selects and configures one storage.StorageBackend. Exactly
+// one of the typed sub-configs should be set, matching Type.
+type BackendConfig struct {
+	Type   string            `json:"type"`
+	S3     *storages3.Config `json:"s3,omitempty"`
+	Local  *local.Config     `json:"local,omitempty"`
+	SSH    *ssh.Config       `json:"ssh,omitempty"`
+	WebDAV *webdav.Config    `json:"webdav,omitempty"`
+	Azure  *azure.Config     `json:"azure,omitempty"`
+}
+
+// storageBackends returns the backends this run should fan out to,
+// falling back to the legacy single-bucket S3 fields when Backends is
+// empty so older config files keep working unchanged.
+func storageBackends() ([]storage.StorageBackend, error) {
+	cfgs := config.Backends
+	if len(cfgs) == 0 {
+		cfgs = []BackendConfig{{
+			Type: "s3",
+			S3: &storages3.Config{
+				BucketName: config.BucketName,
+				AccessKey:  config.AccessKey,
+				SecretKey:  config.SecretKey,
+				Endpoint:   config.Endpoint,
+				Region:     config.Region,
+			},
+		}}
+	}
+
+	backends := make([]storage.StorageBackend, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		backend, err := newStorageBackend(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("backend %d: %w", i, err)
+		}
+		backends = append(backends, backend)
+	}
+	return backends, nil
+}
+
+// archiveEncryptor builds the Encryptor described by the config's Passphrase
+// / AgeRecipients fields, or returns (nil, nil) when neither is set.
+func archiveEncryptor() (encrypt.Encryptor, error) {
+	return encrypt.New(encrypt.Config{
+		Passphrase:    config.Passphrase,
+		AgeRecipients: config.AgeRecipients,
+	})
+}
+
+// decryptArchiveStream wraps r, an age-encrypted archive stream, using
+// config.Passphrase when it is set and config.AgeIdentityPath otherwise, the
+// same precedence encrypt.New uses when encrypting. The archive itself is
+// not inspected, so an archive encrypted the other way fails to decrypt.
+func decryptArchiveStream(r io.Reader) (io.Reader, error) {
+	switch {
+	case config.Passphrase != "":
+		return encrypt.Decrypt(r, config.Passphrase)
+	case config.AgeIdentityPath != "":
+		return encrypt.DecryptWithIdentityFile(r, config.AgeIdentityPath)
+	default:
+		return nil, errors.New("archive is encrypted but no passphrase or age_identity_path is configured")
+	}
+}
+
+// archiveSuffix returns the object key suffix createArchiveAndUpload should
+// append for the given encryptor, "" when archives are uploaded unencrypted.
+// encrypt.New only ever builds age-based encryptors, so the suffix is always
+// ".age".
+func archiveSuffix(encryptor encrypt.Encryptor) string {
+	if encryptor == nil {
+		return ""
+	}
+	return ".age"
+}
+
+func newStorageBackend(cfg BackendConfig) (storage.StorageBackend, error) {
+	switch cfg.Type {
+	case "s3", "":
+		if cfg.S3 == nil {
+			return nil, fmt.Errorf("backend type %q requires an s3 config", cfg.Type)
+		}
+		return storages3.New(*cfg.S3)
+	case "local":
+		if cfg.Local == nil {
+			return nil, fmt.Errorf("backend type %q requires a local config", cfg.Type)
+		}
+		return local.New(*cfg.Local)
+	case "ssh":
+		if cfg.SSH == nil {
+			return nil, fmt.Errorf("backend type %q requires an ssh config", cfg.Type)
+		}
+		return ssh.New(*cfg.SSH)
+	case "webdav":
+		if cfg.WebDAV == nil {
+			return nil, fmt.Errorf("backend type %q requires a webdav config", cfg.Type)
+		}
+		return webdav.New(*cfg.WebDAV)
+	case "azure":
+		if cfg.Azure == nil {
+			return nil, fmt.Errorf("backend type %q requires an azure config", cfg.Type)
+		}
+		return azure.New(*cfg.Azure)
+	default:
+		return nil, fmt.Errorf("unknown backend type %q", cfg.Type)
+	}
 }
 
 type SnapshotStatus struct {
@@ -72,13 +214,31 @@ type Metadata struct {
 	UncommpresedSize int64  `json:"uncommpresedSize"`
 	DataDirSha256    string `json:"dataDirSha256"`
 	Status           string `json:"status"`
+
+	// EncryptionScheme and EncryptionKeyFingerprint are empty when the
+	// archive was uploaded unencrypted. restoreArchive checks
+	// EncryptionScheme before decrypting; EncryptionKeyFingerprint is only
+	// recorded.
+	EncryptionScheme         string `json:"encryptionScheme,omitempty"`
+	EncryptionKeyFingerprint string `json:"encryptionKeyFingerprint,omitempty"`
 }
 
 var config *Config
 
+var (
+	runMode          string
+	decryptIn        string
+	decryptOut       string
+	restoreTimestamp string
+)
+
 func init() {
 	var configFilePath string
 	flag.StringVar(&configFilePath, "config", "", "Path to the configuration file")
+	flag.StringVar(&runMode, "mode", "backup", "Operation mode: backup, restore or decrypt")
+	flag.StringVar(&decryptIn, "in", "", "decrypt mode: object key of the encrypted archive on the configured storage backend")
+	flag.StringVar(&decryptOut, "out", "", "decrypt mode: local path to write the decrypted archive")
+	flag.StringVar(&restoreTimestamp, "timestamp", "", "restore mode: snapshot timestamp to restore, defaults to the latest one")
 	flag.Parse()
 
 	var err error
@@ -141,27 +301,28 @@ func calculateNextRun() {
 	log.Println("Timer: Next run", nextRun.Format("2006-01-02 15:04:05"))
 }
 
-func pruneOldSnapshots() error {
-	sess, err := session.NewSession(&aws.Config{
-		Region:           aws.String(config.Region),
-		Credentials:      credentials.NewStaticCredentials(config.AccessKey, config.SecretKey, ""),
-		Endpoint:         aws.String(config.Endpoint),
-		S3ForcePathStyle: aws.Bool(true),
-	})
-	if err != nil {
-		return err
-	}
+// archiveSuffixes lists every suffix an uploaded archive may carry, plain or
+// encrypted, so pruneOldSnapshots still recognizes and cleans up encrypted
+// snapshots written by createArchiveAndUpload.
+var archiveSuffixes = []string{".tar.gz", ".tar.gz.age", ".tar.gz.enc"}
 
-	bucketName := config.BucketName
-	logPrefix := "PruneOldSnapshots: "
-	directoryPrefix := fmt.Sprintf("%s/%s/", config.Protocol, config.Network)
-	fileNameSuffixes := []string{".tar.gz", "-metadata.json"}
+const metadataSuffix = "-metadata.json"
+const timestampLayout = "20060102-150405"
+
+// pruneStats reports how many old archive and metadata files a prune pass
+// deleted, for the eventual backup notification.
+type pruneStats struct {
+	ArchivesDeleted int
+	MetadataDeleted int
+}
 
-	svc := s3.New(sess)
+func pruneOldSnapshots(backend storage.StorageBackend) (pruneStats, error) {
+	logPrefix := fmt.Sprintf("PruneOldSnapshots[%s]: ", backend.Name())
+	directoryPrefix := fmt.Sprintf("%s/%s/", config.Protocol, config.Network)
 
-	resp, err := svc.ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String(bucketName), Prefix: aws.String(directoryPrefix)})
+	objects, err := backend.List(context.Background(), directoryPrefix)
 	if err != nil {
-		return err
+		return pruneStats{}, err
 	}
 
 	type fileWithTimestamp struct {
@@ -171,23 +332,30 @@ func pruneOldSnapshots() error {
 	tarFiles := []fileWithTimestamp{}
 	jsonFiles := []fileWithTimestamp{}
 
-	log.Printf("%sLooking for old snapshot files in bucket %s", logPrefix, bucketName)
-	for _, item := range resp.Contents {
-		key := *item.Key
-		for _, suffix := range fileNameSuffixes {
-			if strings.HasSuffix(key, suffix) {
-				timestampStr := strings.TrimSuffix(strings.TrimPrefix(key, directoryPrefix), suffix)
-				timestamp, err := time.Parse("20060102-150405", timestampStr)
-				if err != nil {
-					return err
-				}
-				file := fileWithTimestamp{key: key, timestamp: timestamp}
-				if suffix == ".tar.gz" {
+	log.Printf("%sLooking for old snapshot files", logPrefix)
+	for _, object := range objects {
+		key := object.Key
+		trimmed := strings.TrimPrefix(key, directoryPrefix)
+		if len(trimmed) < len(timestampLayout) {
+			continue
+		}
+
+		timestamp, err := time.Parse(timestampLayout, trimmed[:len(timestampLayout)])
+		if err != nil {
+			continue
+		}
+		file := fileWithTimestamp{key: key, timestamp: timestamp}
+
+		rest := trimmed[len(timestampLayout):]
+		switch {
+		case rest == metadataSuffix:
+			jsonFiles = append(jsonFiles, file)
+		default:
+			for _, suffix := range archiveSuffixes {
+				if rest == suffix {
 					tarFiles = append(tarFiles, file)
-				} else if suffix == "-metadata.json" {
-					jsonFiles = append(jsonFiles, file)
+					break
 				}
-				break
 			}
 		}
 	}
@@ -200,35 +368,37 @@ func pruneOldSnapshots() error {
 		return jsonFiles[i].timestamp.Before(jsonFiles[j].timestamp)
 	})
 
+	var stats pruneStats
+
 	// Delete old .tar.gz files
 	if len(tarFiles) > config.SnapshotToKeep {
-		log.Printf("%sFound %d .tar.gz files in bucket %s, deleting older ones", logPrefix, len(tarFiles), bucketName)
+		log.Printf("%sFound %d .tar.gz files, deleting older ones", logPrefix, len(tarFiles))
 		for _, file := range tarFiles[:len(tarFiles)-config.SnapshotToKeep] {
 			log.Printf("%sDeleting .tar.gz file %s", logPrefix, file.key)
-			_, err := svc.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(bucketName), Key: aws.String(file.key)})
-			if err != nil {
-				return err
+			if err := backend.Delete(context.Background(), file.key); err != nil {
+				return stats, err
 			}
+			stats.ArchivesDeleted++
 		}
 	} else {
-		log.Printf("%sFound %d .tar.gz files in bucket %s, nothing to delete", logPrefix, len(tarFiles), bucketName)
+		log.Printf("%sFound %d .tar.gz files, nothing to delete", logPrefix, len(tarFiles))
 	}
 
 	// Delete old -metadata.json files
 	if len(jsonFiles) > config.SnapshotToKeep {
-		log.Printf("%sFound %d -metadata.json files in bucket %s, deleting older ones", logPrefix, len(jsonFiles), bucketName)
+		log.Printf("%sFound %d -metadata.json files, deleting older ones", logPrefix, len(jsonFiles))
 		for _, file := range jsonFiles[:len(jsonFiles)-config.SnapshotToKeep] {
 			log.Printf("%sDeleting -metadata.json file %s", logPrefix, file.key)
-			_, err := svc.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(bucketName), Key: aws.String(file.key)})
-			if err != nil {
-				return err
+			if err := backend.Delete(context.Background(), file.key); err != nil {
+				return stats, err
 			}
+			stats.MetadataDeleted++
 		}
 	} else {
-		log.Printf("%sFound %d -metadata.json files in bucket %s, nothing to delete", logPrefix, len(jsonFiles), bucketName)
+		log.Printf("%sFound %d -metadata.json files, nothing to delete", logPrefix, len(jsonFiles))
 	}
 
-	return nil
+	return stats, nil
 }
 
 func getContainerID(containerName string) (string, error) {
@@ -254,6 +424,180 @@ func getContainerID(containerName string) (string, error) {
 	return "", fmt.Errorf("container with name %s not found", containerName)
 }
 
+// Container labels that opt a managed container into the hook/stop behavior
+// below. stop-during-backup defaults to "true" (the original behavior) and
+// only needs to be set to "false" to leave the container running while its
+// pre/post-backup hooks still execute.
+const (
+	labelStopDuringBackup = "snapshot-service.stop-during-backup"
+	labelPreBackupHook    = "snapshot-service.pre-backup"
+	labelPostBackupHook   = "snapshot-service.post-backup"
+	labelHookTimeout      = "snapshot-service.hook-timeout"
+)
+
+const defaultHookTimeout = 30 * time.Second
+
+// hookResults accumulates the outcome of every pre/post-backup hook run
+// during the current backup, so they can be included in the run's
+// notification alongside the per-backend upload results. runBackupProcess
+// resets it at the start of each run.
+var hookResults []notify.HookResult
+
+// hookTimeout reads the per-container hook timeout override, falling back
+// to defaultHookTimeout when the label is absent or invalid.
+func hookTimeout(labels map[string]string) time.Duration {
+	raw, ok := labels[labelHookTimeout]
+	if !ok {
+		return defaultHookTimeout
+	}
+	seconds, err := strconv.Atoi(raw)
+	if err != nil || seconds <= 0 {
+		return defaultHookTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// readExecOutput demultiplexes a non-TTY ContainerExecAttach stream, which
+// stdcopy-frames stdout and stderr together, and returns their combined,
+// trimmed text.
+func readExecOutput(reader io.Reader) (string, error) {
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, reader); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String() + stderr.String()), nil
+}
+
+// runContainerHook runs command inside containerID via docker exec, logging
+// its combined stdout/stderr and failing if it does not exit zero within
+// timeout. stage is "pre-backup" or "post-backup", used in the log line, the
+// recorded HookResult and the returned error.
+func runContainerHook(cli *client.Client, containerID, containerName, stage, command string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	execConfig := types.ExecConfig{
+		Cmd:          []string{"sh", "-c", command},
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	execID, err := cli.ContainerExecCreate(ctx, containerID, execConfig)
+	if err != nil {
+		return fmt.Errorf("create exec: %w", err)
+	}
+
+	attach, err := cli.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
+	if err != nil {
+		return fmt.Errorf("attach exec: %w", err)
+	}
+	defer attach.Close()
+
+	trimmedOutput, err := readExecOutput(attach.Reader)
+	if err != nil {
+		return fmt.Errorf("read exec output: %w", err)
+	}
+	if trimmedOutput != "" {
+		log.Printf("ContainerService[%s/%s]: %s", containerName, stage, trimmedOutput)
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return fmt.Errorf("inspect exec: %w", err)
+	}
+
+	hookErr := ""
+	if inspect.ExitCode != 0 {
+		hookErr = fmt.Sprintf("exited with code %d", inspect.ExitCode)
+	}
+	hookResults = append(hookResults, notify.HookResult{
+		Container: containerName,
+		Stage:     stage,
+		Output:    trimmedOutput,
+		Error:     hookErr,
+	})
+
+	if hookErr != "" {
+		return fmt.Errorf("%s hook %s", stage, hookErr)
+	}
+	return nil
+}
+
+// dockerExecer adapts the Docker client to chainprobe.Execer, running
+// command inside containerName and returning its trimmed combined output.
+func dockerExecer(containerName string) chainprobe.Execer {
+	return func(ctx context.Context, command []string) (string, error) {
+		containerID, err := getContainerID(containerName)
+		if err != nil {
+			return "", err
+		}
+
+		cli, err := getDockerClient()
+		if err != nil {
+			return "", err
+		}
+		defer cli.Close()
+
+		execConfig := types.ExecConfig{
+			Cmd:          command,
+			AttachStdout: true,
+			AttachStderr: true,
+		}
+		execID, err := cli.ContainerExecCreate(ctx, containerID, execConfig)
+		if err != nil {
+			return "", fmt.Errorf("create exec: %w", err)
+		}
+
+		attach, err := cli.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
+		if err != nil {
+			return "", fmt.Errorf("attach exec: %w", err)
+		}
+		defer attach.Close()
+
+		output, err := readExecOutput(attach.Reader)
+		if err != nil {
+			return "", fmt.Errorf("read exec output: %w", err)
+		}
+		return output, nil
+	}
+}
+
+// unknownBlockInfo is recorded when a chain probe is unconfigured or fails,
+// so the metadata still makes clear the values weren't simply omitted.
+const unknownBlockInfo = "Unknown"
+
+// probeChain queries config.ChainProbeContainer (or the first of
+// ContainerNames) for the current block height and hash via the
+// chainprobe.Probe matching config.Protocol. A missing or failing probe is
+// non-fatal: it just leaves both values as unknownBlockInfo.
+func probeChain() (height string, hash string) {
+	height, hash = unknownBlockInfo, unknownBlockInfo
+
+	probe, err := chainprobe.New(config.Protocol)
+	if err != nil {
+		log.Printf("ChainProbe: %v", err)
+		return height, hash
+	}
+
+	containerName := config.ChainProbeContainer
+	if containerName == "" && len(config.ContainerNames) > 0 {
+		containerName = config.ContainerNames[0]
+	}
+	if containerName == "" {
+		log.Printf("ChainProbe: no container configured to probe")
+		return height, hash
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultHookTimeout)
+	defer cancel()
+
+	probedHeight, probedHash, err := probe.Probe(ctx, dockerExecer(containerName))
+	if err != nil {
+		log.Printf("ChainProbe[%s/%s]: error: %v", config.Protocol, containerName, err)
+		return height, hash
+	}
+	return probedHeight, probedHash
+}
+
 func stopContainer(containerName string) error {
 	containerID, err := getContainerID(containerName)
 	if err != nil {
@@ -266,6 +610,23 @@ func stopContainer(containerName string) error {
 	}
 	defer cli.Close()
 
+	info, err := cli.ContainerInspect(context.Background(), containerID)
+	if err != nil {
+		return err
+	}
+	labels := info.Config.Labels
+
+	if hook := labels[labelPreBackupHook]; hook != "" {
+		if err := runContainerHook(cli, containerID, containerName, "pre-backup", hook, hookTimeout(labels)); err != nil {
+			log.Printf("ContainerService: pre-backup hook for %s failed: %v", containerName, err)
+		}
+	}
+
+	if labels[labelStopDuringBackup] == "false" {
+		log.Printf("ContainerService: Container %s has %s=false, leaving it running\n", containerName, labelStopDuringBackup)
+		return nil
+	}
+
 	timeout := int(10)
 	stopOptions := container.StopOptions{
 		Timeout: &timeout,
@@ -288,9 +649,24 @@ func startContainerByName(containerName string) error {
 	}
 	defer cli.Close()
 
-	if err := cli.ContainerStart(context.Background(), containerID, types.ContainerStartOptions{}); err != nil {
+	info, err := cli.ContainerInspect(context.Background(), containerID)
+	if err != nil {
 		return err
 	}
+	labels := info.Config.Labels
+
+	if labels[labelStopDuringBackup] != "false" {
+		if err := cli.ContainerStart(context.Background(), containerID, types.ContainerStartOptions{}); err != nil {
+			return err
+		}
+	}
+
+	if hook := labels[labelPostBackupHook]; hook != "" {
+		if err := runContainerHook(cli, containerID, containerName, "post-backup", hook, hookTimeout(labels)); err != nil {
+			log.Printf("ContainerService: post-backup hook for %s failed: %v", containerName, err)
+		}
+	}
+
 	return nil
 }
 
@@ -316,31 +692,14 @@ func startContainers(containerNames []string) error {
 	return nil
 }
 
-func uploadToS3(filePath, bucket, key string) error {
-	sess, err := session.NewSession(&aws.Config{
-		Region:           aws.String(config.Region),
-		Credentials:      credentials.NewStaticCredentials(config.AccessKey, config.SecretKey, ""),
-		Endpoint:         aws.String(config.Endpoint),
-		S3ForcePathStyle: aws.Bool(true),
-	})
-	if err != nil {
-		return err
-	}
-
+func uploadToBackend(backend storage.StorageBackend, filePath, key string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	uploader := s3manager.NewUploader(sess)
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-		Body:   file,
-	})
-
-	return err
+	return backend.Upload(context.Background(), key, file)
 }
 
 func CalculateDirectorySize(path string) (int64, error) {
@@ -354,98 +713,211 @@ func CalculateDirectorySize(path string) (int64, error) {
 	return size, err
 }
 
-func createTarGzToS3(bucketName string, key string, folderPath string) error {
-	log.Println("ArchiveCreate: Create and Stream snapshot to S3")
-	sess, err := session.NewSession(&aws.Config{
-		Region:           aws.String(config.Region),
-		Credentials:      credentials.NewStaticCredentials(config.AccessKey, config.SecretKey, ""),
-		Endpoint:         aws.String(config.Endpoint),
-		S3ForcePathStyle: aws.Bool(true),
-	})
-	if err != nil {
-		return err
+// backendUploadResult is the per-backend outcome of a fan-out upload, used
+// by runBackupProcess to report partial failures instead of aborting the
+// whole run on the first one.
+type backendUploadResult struct {
+	Backend string
+	Err     error
+}
+
+// countingWriter tallies the bytes written through it, used to report the
+// final (compressed, possibly encrypted) archive size in notifications.
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// createArchiveAndUpload tars, gzips and (if encryptor is non-nil) encrypts
+// folderPath, streaming the result to every backend at once so the archive
+// never touches disk unencrypted. The pipeline is tar -> gzip -> encrypt ->
+// backend. Each backend gets its own pipe, but all pipes are fed by a single
+// io.MultiWriter, which writes to them one at a time in order: a slow or
+// stuck backend therefore stalls the write to every other backend too, not
+// just its own. Each backend's outcome is still reported back as its own
+// result. archiveSize reports the number of bytes actually sent to the
+// backends (post compression/encryption).
+func createArchiveAndUpload(backends []storage.StorageBackend, encryptor encrypt.Encryptor, key string, folderPath string) (results []backendUploadResult, archiveSize int64) {
+	log.Println("ArchiveCreate: Create and stream snapshot to configured backends")
+
+	writers := make([]io.Writer, len(backends))
+	results = make([]backendUploadResult, len(backends))
+	done := make(chan struct{}, len(backends))
+
+	for i, backend := range backends {
+		pr, pw := io.Pipe()
+		writers[i] = pw
+
+		go func(i int, backend storage.StorageBackend, pr *io.PipeReader) {
+			defer func() { done <- struct{}{} }()
+			err := backend.Upload(context.Background(), key, pr)
+			io.Copy(io.Discard, pr) // drain so the writer side never blocks on us
+			pr.Close()
+			results[i] = backendUploadResult{Backend: backend.Name(), Err: err}
+		}(i, backend, pr)
 	}
 
-	uploader := s3manager.NewUploader(sess)
+	closeWriters := func(err error) {
+		for _, w := range writers {
+			w.(*io.PipeWriter).CloseWithError(err)
+		}
+		for range backends {
+			<-done
+		}
+	}
 
-	pr, pw := io.Pipe()
+	counter := &countingWriter{}
+	var archiveDst io.Writer = io.MultiWriter(append(writers, counter)...)
+	var encDst io.WriteCloser
+	if encryptor != nil {
+		var err error
+		encDst, err = encryptor.Wrap(archiveDst)
+		if err != nil {
+			for i, backend := range backends {
+				results[i] = backendUploadResult{Backend: backend.Name(), Err: err}
+			}
+			closeWriters(err)
+			return results, 0
+		}
+		archiveDst = encDst
+	}
 
-	gw, err := pgzip.NewWriterLevel(pw, pgzip.BestSpeed)
+	gw, err := pgzip.NewWriterLevel(archiveDst, pgzip.BestSpeed)
 	if err != nil {
-		return err
+		for i, backend := range backends {
+			results[i] = backendUploadResult{Backend: backend.Name(), Err: err}
+		}
+		closeWriters(err)
+		return results, 0
 	}
 
 	tw := tar.NewWriter(gw)
 
-	go func() {
-		defer pw.Close()
-		defer gw.Close()
-		defer tw.Close()
-
-		err := filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-
-			// If the current file is in the ignore list, skip it
-			for _, ignore := range config.IgnoreFiles {
-				if filepath.Base(path) == ignore {
-					log.Printf("Skipping %s", path)
-					return nil
-				}
-			}
+	walkErr := filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 
-			if info.IsDir() {
+		// If the current file is in the ignore list, skip it
+		for _, ignore := range config.IgnoreFiles {
+			if filepath.Base(path) == ignore {
+				log.Printf("Skipping %s", path)
 				return nil
 			}
+		}
 
-			relPath, err := filepath.Rel(folderPath, path)
-			if err != nil {
-				return err
-			}
-
-			log.Printf("ArchiveCreate: Adding %s", relPath)
+		if info.IsDir() {
+			return nil
+		}
 
-			header, err := tar.FileInfoHeader(info, relPath)
-			if err != nil {
-				return err
-			}
+		relPath, err := filepath.Rel(folderPath, path)
+		if err != nil {
+			return err
+		}
 
-			header.Name = relPath
+		log.Printf("ArchiveCreate: Adding %s", relPath)
 
-			if err := tw.WriteHeader(header); err != nil {
-				return err
-			}
+		header, err := tar.FileInfoHeader(info, relPath)
+		if err != nil {
+			return err
+		}
 
-			file, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-			defer file.Close()
+		header.Name = relPath
 
-			_, err = io.Copy(tw, file)
+		if err := tw.WriteHeader(header); err != nil {
 			return err
-		})
+		}
 
+		file, err := os.Open(path)
 		if err != nil {
-			return
+			return err
 		}
-	}()
+		defer file.Close()
 
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-		Body:   pr,
+		_, err = io.Copy(tw, file)
+		return err
 	})
 
+	tw.Close()
+	gw.Close()
+	if encDst != nil && walkErr == nil {
+		walkErr = encDst.Close()
+	}
+	closeWriters(walkErr)
+
+	if walkErr != nil {
+		for i, backend := range backends {
+			if results[i].Err == nil {
+				results[i] = backendUploadResult{Backend: backend.Name(), Err: walkErr}
+			}
+		}
+	}
+
+	return results, counter.n
+}
+
+// runDecryptCommand implements `snapshot-service -mode=decrypt`: it streams
+// an archive previously produced by createArchiveAndUpload, identified by
+// its object key on the configured storage backend, back through the
+// decryptor and writes the resulting plain tar.gz to -out.
+func runDecryptCommand() error {
+	if decryptIn == "" || decryptOut == "" {
+		return errors.New("decrypt mode requires -in and -out")
+	}
+	if config.Passphrase == "" && config.AgeIdentityPath == "" {
+		return errors.New("decrypt mode requires a passphrase or age_identity_path in the config file")
+	}
+
+	backends, err := storageBackends()
+	if err != nil {
+		return fmt.Errorf("error configuring storage backends: %w", err)
+	}
+	if len(backends) == 0 {
+		return errors.New("no storage backends configured")
+	}
+	backend := backends[0]
+
+	log.Printf("Decrypt[%s]: Downloading archive %s", backend.Name(), decryptIn)
+	in, err := backend.Download(context.Background(), decryptIn)
+	if err != nil {
+		return fmt.Errorf("download %s: %w", decryptIn, err)
+	}
+	defer in.Close()
+
+	plaintext, err := decryptArchiveStream(in)
+	if err != nil {
+		return fmt.Errorf("decrypt %s: %w", decryptIn, err)
+	}
+
+	out, err := os.Create(decryptOut)
 	if err != nil {
 		return err
 	}
+	defer out.Close()
 
-	return nil
+	_, err = io.Copy(out, plaintext)
+	return err
 }
 
 func main() {
+	if runMode == "decrypt" {
+		if err := runDecryptCommand(); err != nil {
+			log.Fatalf("Error running decrypt command: %v", err)
+		}
+		return
+	}
+
+	if runMode == "restore" {
+		if err := runRestoreProcess(); err != nil {
+			log.Fatalf("Error running restore process: %v", err)
+		}
+		return
+	}
+
 	log.Printf("Snapshot service started. Protocol: %s, Network: %s, Crontime: %s",
 		config.Protocol, config.Network, config.CrontTime)
 
@@ -528,40 +1000,40 @@ func hashDirectory(dir string) (string, error) {
 	return hashStr, nil
 }
 
-func uploadAndCleanUp(file string, bucket string, key string) error {
-	log.Println("UploadS3: Uploading state file to bucket", bucket)
-	err := uploadToS3(file, bucket, key)
-	if err != nil {
-		log.Printf("UploadS3: Error uploading to S3: %v", err)
-		return err
+func uploadAndCleanUp(backends []storage.StorageBackend, file string, key string) []backendUploadResult {
+	results := make([]backendUploadResult, len(backends))
+	for i, backend := range backends {
+		log.Printf("Upload[%s]: Uploading %s", backend.Name(), file)
+		err := uploadToBackend(backend, file, key)
+		if err != nil {
+			log.Printf("Upload[%s]: Error uploading %s: %v", backend.Name(), file, err)
+		}
+		results[i] = backendUploadResult{Backend: backend.Name(), Err: err}
 	}
 
 	log.Println("CleanUp: Removing ", file)
-	err = os.Remove(file)
-	if err != nil {
-		log.Printf("cleanUp: Error Statefile: %v", err)
-		return err
+	if err := os.Remove(file); err != nil {
+		log.Printf("cleanUp: Error removing %s: %v", file, err)
 	}
 
-	return nil
+	return results
 }
 
-func createSnapShotMetadata(key, time, status string) error {
-	DataFile := fmt.Sprintf("%s.tar.gz", time)
+func createSnapShotMetadata(backends []storage.StorageBackend, encryptor encrypt.Encryptor, key, time, status, blockHeight, blockHash string) (Metadata, []backendUploadResult, error) {
+	DataFile := fmt.Sprintf("%s.tar.gz%s", time, archiveSuffix(encryptor))
 	metaDataFile := fmt.Sprintf("%s-metadata.json", time)
 	metaDataLatest := "snapshot-latest.json"
 	metaDataFileKey := fmt.Sprintf("%s/%s", key, metaDataFile)
 	metaDataLatestKey := fmt.Sprintf("%s/%s", key, metaDataLatest)
-	notYetImplemented := "Unknown"
 
 	dirSize, err := CalculateDirectorySize(config.FilePath)
 	if err != nil {
-		return err
+		return Metadata{}, nil, err
 	}
 
 	hashString, err := hashDirectory(config.FilePath)
 	if err != nil {
-		return err
+		return Metadata{}, nil, err
 	}
 
 	metadata := Metadata{
@@ -572,56 +1044,93 @@ func createSnapShotMetadata(key, time, status string) error {
 		ProtocolHistory:  config.ProtocolHistory,
 		ProtocolVersion:  config.ProtocolVer,
 		SnapshotVersion:  appVersion,
-		BlockHash:        notYetImplemented,
-		BlockHeight:      notYetImplemented,
+		BlockHash:        blockHash,
+		BlockHeight:      blockHeight,
 		UncommpresedSize: dirSize,
 		DataDirSha256:    hashString,
 		Status:           status,
 	}
 
-	err = WriteMetadataToFile(metadata, metaDataFile)
-	if err != nil {
-		return err
+	if encryptor != nil {
+		metadata.EncryptionScheme = encryptor.Scheme()
+		metadata.EncryptionKeyFingerprint = encryptor.Fingerprint()
 	}
 
-	err = WriteMetadataToFile(metadata, metaDataLatest)
-	if err != nil {
-		return err
+	if err := WriteMetadataToFile(metadata, metaDataFile); err != nil {
+		return metadata, nil, err
 	}
 
-	err = uploadAndCleanUp(metaDataFile, config.BucketName, metaDataFileKey)
-	if err != nil {
-		return err
+	if err := WriteMetadataToFile(metadata, metaDataLatest); err != nil {
+		return metadata, nil, err
 	}
 
-	err = uploadAndCleanUp(metaDataLatest, config.BucketName, metaDataLatestKey)
-	if err != nil {
-		return err
-	}
+	results := uploadAndCleanUp(backends, metaDataFile, metaDataFileKey)
+	results = append(results, uploadAndCleanUp(backends, metaDataLatest, metaDataLatestKey)...)
 
-	return nil
+	return metadata, results, nil
+}
+
+// logBackendResults logs every per-backend outcome and reports whether at
+// least one of them failed, so callers can mark the run as partially failed
+// without treating one backend's error as fatal to the others.
+func logBackendResults(action string, results []backendUploadResult) bool {
+	hadError := false
+	for _, result := range results {
+		if result.Err != nil {
+			hadError = true
+			log.Printf("%s[%s]: error: %v", action, result.Backend, result.Err)
+			continue
+		}
+		log.Printf("%s[%s]: ok", action, result.Backend)
+	}
+	return hadError
 }
 
 func runBackupProcess() error {
-	pruneOldSnapshots()
+	start := time.Now()
+	hookResults = nil
+
+	backends, err := storageBackends()
+	if err != nil {
+		return fmt.Errorf("error configuring storage backends: %v", err)
+	}
+
+	encryptor, err := archiveEncryptor()
+	if err != nil {
+		return fmt.Errorf("error configuring archive encryption: %v", err)
+	}
+
+	var prune pruneStats
+	for _, backend := range backends {
+		backendStats, err := pruneOldSnapshots(backend)
+		if err != nil {
+			log.Printf("PruneOldSnapshots[%s]: error: %v", backend.Name(), err)
+		}
+		prune.ArchivesDeleted += backendStats.ArchivesDeleted
+		prune.MetadataDeleted += backendStats.MetadataDeleted
+	}
 	status := "success"
 
 	currentTime := currentDateTime()
 	key := fmt.Sprintf("%s/%s", config.Protocol, config.Network)
-	tarFile := fmt.Sprintf("%s/%s.tar.gz", key, currentTime)
+	tarFile := fmt.Sprintf("%s/%s.tar.gz%s", key, currentTime, archiveSuffix(encryptor))
+
+	blockHeight, blockHash := probeChain()
 
 	if err := stopContainers(config.ContainerNames); err != nil {
 		return fmt.Errorf("error stopping containers: %v", err)
 	}
 
-	err := createTarGzToS3(config.BucketName, tarFile, config.FilePath)
-	if err != nil {
+	archiveResults, archiveSize := createArchiveAndUpload(backends, encryptor, tarFile, config.FilePath)
+	if logBackendResults("ArchiveUpload", archiveResults) {
 		status = "error"
 	}
 
-	err = createSnapShotMetadata(key, currentTime, status)
+	metadata, metadataResults, err := createSnapShotMetadata(backends, encryptor, key, currentTime, status, blockHeight, blockHash)
 	if err != nil {
 		status = "error"
+	} else if logBackendResults("MetadataUpload", metadataResults) {
+		status = "error"
 	}
 
 	if err := startContainers(config.ContainerNames); err != nil {
@@ -631,9 +1140,224 @@ func runBackupProcess() error {
 	calculateNextRun()
 	log.Printf("Service: %s Snapshot finished", config.Protocol)
 
+	notifyBackupResult(metadata, status, time.Since(start), archiveSize, archiveResults, prune, hookResults)
+
 	if status == "error" {
 		return errors.New("runBackupProcess finished with errors")
 	}
 
 	return nil
 }
+
+// notifyBackupResult dispatches the configured notification, if any, for a
+// finished run. A failed notification is only logged: it must never mask
+// the backup's own success or failure.
+func notifyBackupResult(metadata Metadata, status string, elapsed time.Duration, archiveSize int64, archiveResults []backendUploadResult, prune pruneStats, hooks []notify.HookResult) {
+	if len(config.Notifications) == 0 {
+		return
+	}
+
+	backendResults := make([]notify.BackendResult, 0, len(archiveResults))
+	for _, result := range archiveResults {
+		errText := ""
+		if result.Err != nil {
+			errText = result.Err.Error()
+		}
+		backendResults = append(backendResults, notify.BackendResult{Backend: result.Backend, Error: errText})
+	}
+
+	err := notify.Send(notify.Config{
+		URLs:         config.Notifications,
+		TemplatePath: config.NotificationTemplate,
+	}, notify.Context{
+		Status: status,
+		Snapshot: notify.SnapshotInfo{
+			DateTime:         metadata.DateTime,
+			FileName:         metadata.FileName,
+			Network:          metadata.Network,
+			Protocol:         metadata.Protocol,
+			ProtocolVersion:  metadata.ProtocolVersion,
+			BlockHash:        metadata.BlockHash,
+			BlockHeight:      metadata.BlockHeight,
+			DataDirSha256:    metadata.DataDirSha256,
+			UncompressedSize: metadata.UncommpresedSize,
+		},
+		Elapsed:        elapsed,
+		ArchiveSize:    archiveSize,
+		BackendResults: backendResults,
+		Prune: notify.PruneStats{
+			ArchivesDeleted: prune.ArchivesDeleted,
+			MetadataDeleted: prune.MetadataDeleted,
+		},
+		HookResults: hooks,
+	})
+	if err != nil {
+		log.Printf("Notify: error sending notification: %v", err)
+	}
+}
+
+func runRestoreProcess() error {
+	backends, err := storageBackends()
+	if err != nil {
+		return fmt.Errorf("error configuring storage backends: %v", err)
+	}
+	if len(backends) == 0 {
+		return errors.New("no storage backends configured")
+	}
+	backend := backends[0]
+
+	key := fmt.Sprintf("%s/%s", config.Protocol, config.Network)
+
+	metadataKey := fmt.Sprintf("%s/snapshot-latest.json", key)
+	if restoreTimestamp != "" {
+		metadataKey = fmt.Sprintf("%s/%s-metadata.json", key, restoreTimestamp)
+	}
+
+	log.Printf("Restore[%s]: Fetching metadata %s", backend.Name(), metadataKey)
+	metaReader, err := backend.Download(context.Background(), metadataKey)
+	if err != nil {
+		return fmt.Errorf("error downloading metadata %s: %w", metadataKey, err)
+	}
+	defer metaReader.Close()
+
+	var metadata Metadata
+	if err := json.NewDecoder(metaReader).Decode(&metadata); err != nil {
+		return fmt.Errorf("error decoding metadata %s: %w", metadataKey, err)
+	}
+
+	if metadata.Status == "error" {
+		return fmt.Errorf("refusing to restore %s: snapshot status is %q", metadataKey, metadata.Status)
+	}
+
+	archiveKey := fmt.Sprintf("%s/%s", key, metadata.FileName)
+
+	if err := stopContainers(config.ContainerNames); err != nil {
+		return fmt.Errorf("error stopping containers: %v", err)
+	}
+
+	restoreErr := restoreArchive(backend, archiveKey, metadata)
+
+	if err := startContainers(config.ContainerNames); err != nil {
+		if restoreErr != nil {
+			return fmt.Errorf("error starting containers after failed restore (%v): %v", restoreErr, err)
+		}
+		return fmt.Errorf("error starting containers: %v", err)
+	}
+
+	if restoreErr != nil {
+		return restoreErr
+	}
+
+	log.Printf("Restore[%s]: Snapshot %s restored", backend.Name(), metadata.FileName)
+	return nil
+}
+
+// restoreArchive stream-extracts the archive at archiveKey into a staging
+// directory next to config.FilePath, without ever staging the whole archive
+// on disk, verifies the extracted tree hashes to metadata.DataDirSha256, and
+// only then swaps it into config.FilePath. config.FilePath is left untouched
+// if extraction or verification fails, so a corrupt or tampered snapshot
+// never destroys the existing data directory.
+func restoreArchive(backend storage.StorageBackend, archiveKey string, metadata Metadata) error {
+	log.Printf("Restore[%s]: Downloading archive %s", backend.Name(), archiveKey)
+	body, err := backend.Download(context.Background(), archiveKey)
+	if err != nil {
+		return fmt.Errorf("error downloading archive %s: %w", archiveKey, err)
+	}
+	defer body.Close()
+
+	var src io.Reader = body
+	if metadata.EncryptionScheme != "" {
+		if metadata.EncryptionScheme != "age" {
+			return fmt.Errorf("unsupported encryption scheme %q", metadata.EncryptionScheme)
+		}
+		src, err = decryptArchiveStream(src)
+		if err != nil {
+			return fmt.Errorf("error decrypting archive %s: %w", archiveKey, err)
+		}
+	}
+
+	gr, err := pgzip.NewReader(src)
+	if err != nil {
+		return fmt.Errorf("error opening gzip stream: %w", err)
+	}
+	defer gr.Close()
+
+	stagingDir, err := os.MkdirTemp(filepath.Dir(config.FilePath), ".restore-*")
+	if err != nil {
+		return fmt.Errorf("error creating staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading tar stream: %w", err)
+		}
+
+		target := filepath.Join(stagingDir, header.Name)
+		if !pathWithinDir(stagingDir, target) {
+			return fmt.Errorf("error extracting tar stream: entry %q escapes the extraction directory", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			f.Close()
+		}
+	}
+
+	log.Println("Restore: Verifying restored data against recorded checksum")
+	hashString, err := hashDirectory(stagingDir)
+	if err != nil {
+		return fmt.Errorf("error hashing restored data: %w", err)
+	}
+	if hashString != metadata.DataDirSha256 {
+		return fmt.Errorf("restored data checksum %s does not match recorded checksum %s", hashString, metadata.DataDirSha256)
+	}
+
+	// Move the current data directory aside rather than deleting it outright,
+	// so a failed rename below still leaves it recoverable instead of gone.
+	backupDir := config.FilePath + ".pre-restore"
+	if err := os.RemoveAll(backupDir); err != nil {
+		return fmt.Errorf("error clearing stale backup %s: %w", backupDir, err)
+	}
+	if err := os.Rename(config.FilePath, backupDir); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error backing up %s: %w", config.FilePath, err)
+	}
+	if err := os.Rename(stagingDir, config.FilePath); err != nil {
+		os.Rename(backupDir, config.FilePath)
+		return fmt.Errorf("error swapping in restored data: %w", err)
+	}
+	if err := os.RemoveAll(backupDir); err != nil {
+		log.Printf("Restore: warning: error removing pre-restore backup %s: %v", backupDir, err)
+	}
+
+	return nil
+}
+
+// pathWithinDir reports whether target, once cleaned, is contained within
+// dir. It guards tar extraction against zip-slip entries (e.g. a header.Name
+// of "../../etc/passwd") in an archive pulled from a remote backend.
+func pathWithinDir(dir, target string) bool {
+	cleanDir := filepath.Clean(dir) + string(os.PathSeparator)
+	cleanTarget := filepath.Clean(target) + string(os.PathSeparator)
+	return strings.HasPrefix(cleanTarget, cleanDir)
+}
@@ -0,0 +1,160 @@
+// Package chainprobe queries a running chain node container for its current
+// block height and hash, so a snapshot's metadata records which block it was
+// taken at. Each supported protocol talks to the node's local RPC endpoint
+// differently, so New returns the Probe matching a snapshot-service.Config's
+// Protocol field.
+package chainprobe
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Execer runs command inside the chain container and returns its combined
+// stdout/stderr, trimmed. It is implemented by the caller, which owns the
+// Docker client used to reach the container.
+type Execer func(ctx context.Context, command []string) (string, error)
+
+// Probe queries a chain container for its current block height and hash. A
+// failed probe is non-fatal to the backup: the snapshot is still taken, with
+// both fields left as the caller's "unknown" placeholder.
+type Probe interface {
+	Probe(ctx context.Context, exec Execer) (height string, hash string, err error)
+}
+
+// New returns the Probe for protocol, matched case-insensitively against
+// Config.Protocol.
+func New(protocol string) (Probe, error) {
+	switch strings.ToLower(protocol) {
+	case "tezos":
+		return Tezos{}, nil
+	case "cosmos":
+		return Cosmos{}, nil
+	case "ethereum":
+		return Ethereum{}, nil
+	case "substrate":
+		return Substrate{}, nil
+	default:
+		return nil, fmt.Errorf("chainprobe: unsupported protocol %q", protocol)
+	}
+}
+
+// Tezos probes a tezos-node container via its local RPC.
+type Tezos struct{}
+
+func (Tezos) Probe(ctx context.Context, exec Execer) (string, string, error) {
+	out, err := exec(ctx, []string{"sh", "-c", "curl -s http://localhost:8732/chains/main/blocks/head/header"})
+	if err != nil {
+		return "", "", fmt.Errorf("tezos: query head header: %w", err)
+	}
+
+	var header struct {
+		Level int    `json:"level"`
+		Hash  string `json:"hash"`
+	}
+	if err := json.Unmarshal([]byte(out), &header); err != nil {
+		return "", "", fmt.Errorf("tezos: parse head header: %w", err)
+	}
+	return strconv.Itoa(header.Level), header.Hash, nil
+}
+
+// Cosmos probes a cosmos-sdk node container via its local Tendermint RPC.
+type Cosmos struct{}
+
+func (Cosmos) Probe(ctx context.Context, exec Execer) (string, string, error) {
+	out, err := exec(ctx, []string{"sh", "-c", "curl -s http://localhost:26657/status"})
+	if err != nil {
+		return "", "", fmt.Errorf("cosmos: query status: %w", err)
+	}
+
+	var status struct {
+		Result struct {
+			SyncInfo struct {
+				LatestBlockHeight string `json:"latest_block_height"`
+				LatestBlockHash   string `json:"latest_block_hash"`
+			} `json:"sync_info"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		return "", "", fmt.Errorf("cosmos: parse status: %w", err)
+	}
+	return status.Result.SyncInfo.LatestBlockHeight, status.Result.SyncInfo.LatestBlockHash, nil
+}
+
+// Ethereum probes a go-ethereum-compatible node container via its local
+// JSON-RPC endpoint.
+type Ethereum struct{}
+
+func (Ethereum) Probe(ctx context.Context, exec Execer) (string, string, error) {
+	out, err := exec(ctx, []string{"sh", "-c",
+		`curl -s -X POST -H "Content-Type: application/json" ` +
+			`-d '{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["latest",false],"id":1}' ` +
+			`http://localhost:8545`})
+	if err != nil {
+		return "", "", fmt.Errorf("ethereum: query latest block: %w", err)
+	}
+
+	var resp struct {
+		Result struct {
+			Number string `json:"number"`
+			Hash   string `json:"hash"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		return "", "", fmt.Errorf("ethereum: parse latest block: %w", err)
+	}
+
+	height, err := strconv.ParseInt(strings.TrimPrefix(resp.Result.Number, "0x"), 16, 64)
+	if err != nil {
+		return "", "", fmt.Errorf("ethereum: parse block number %q: %w", resp.Result.Number, err)
+	}
+	return strconv.FormatInt(height, 10), resp.Result.Hash, nil
+}
+
+// Substrate probes a substrate-based node container via its local JSON-RPC
+// endpoint.
+type Substrate struct{}
+
+func (Substrate) Probe(ctx context.Context, exec Execer) (string, string, error) {
+	headerOut, err := exec(ctx, []string{"sh", "-c",
+		`curl -s -H "Content-Type: application/json" ` +
+			`-d '{"jsonrpc":"2.0","id":1,"method":"chain_getHeader","params":[]}' ` +
+			`http://localhost:9933`})
+	if err != nil {
+		return "", "", fmt.Errorf("substrate: query header: %w", err)
+	}
+
+	var header struct {
+		Result struct {
+			Number string `json:"number"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(headerOut), &header); err != nil {
+		return "", "", fmt.Errorf("substrate: parse header: %w", err)
+	}
+
+	height, err := strconv.ParseInt(strings.TrimPrefix(header.Result.Number, "0x"), 16, 64)
+	if err != nil {
+		return "", "", fmt.Errorf("substrate: parse block number %q: %w", header.Result.Number, err)
+	}
+
+	hashOut, err := exec(ctx, []string{"sh", "-c",
+		`curl -s -H "Content-Type: application/json" ` +
+			`-d '{"jsonrpc":"2.0","id":1,"method":"chain_getBlockHash","params":[]}' ` +
+			`http://localhost:9933`})
+	if err != nil {
+		return "", "", fmt.Errorf("substrate: query block hash: %w", err)
+	}
+
+	var blockHash struct {
+		Result string `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(hashOut), &blockHash); err != nil {
+		return "", "", fmt.Errorf("substrate: parse block hash: %w", err)
+	}
+
+	return strconv.FormatInt(height, 10), blockHash.Result, nil
+}
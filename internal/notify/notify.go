@@ -0,0 +1,125 @@
+// Package notify renders and dispatches success/failure notifications for
+// a backup run through shoutrrr, so a single list of URLs (Slack, Discord,
+// Telegram, a generic webhook, SMTP, ...) covers every destination a user
+// wants paged.
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/containrrr/shoutrrr"
+)
+
+//go:embed templates/success.tmpl templates/failure.tmpl
+var defaultTemplates embed.FS
+
+// Config selects where notifications go and, optionally, a custom template
+// to render them with.
+type Config struct {
+	URLs         []string `json:"urls"`
+	TemplatePath string   `json:"template_path"`
+}
+
+// SnapshotInfo mirrors the fields of the run's Metadata that are useful in a
+// notification message.
+type SnapshotInfo struct {
+	DateTime         string
+	FileName         string
+	Network          string
+	Protocol         string
+	ProtocolVersion  string
+	BlockHash        string
+	BlockHeight      string
+	DataDirSha256    string
+	UncompressedSize int64
+}
+
+// BackendResult is the per-backend outcome of an upload, for reporting
+// partial failures in the notification body.
+type BackendResult struct {
+	Backend string
+	Error   string
+}
+
+// PruneStats summarizes how many old snapshot files were deleted during the
+// run's prune step.
+type PruneStats struct {
+	ArchivesDeleted int
+	MetadataDeleted int
+}
+
+// HookResult is the outcome of a single pre/post-backup container hook.
+type HookResult struct {
+	Container string
+	Stage     string
+	Output    string
+	Error     string
+}
+
+// Context is the data a notification template is rendered with.
+type Context struct {
+	Status         string
+	Snapshot       SnapshotInfo
+	Elapsed        time.Duration
+	ArchiveSize    int64
+	BackendResults []BackendResult
+	Prune          PruneStats
+	HookResults    []HookResult
+}
+
+// Send renders the configured (or default) template with ctx and dispatches
+// it to every URL in cfg.URLs. A failed notification is returned to the
+// caller but must never be treated as the backup itself having failed.
+func Send(cfg Config, ctx Context) error {
+	if len(cfg.URLs) == 0 {
+		return nil
+	}
+
+	tmpl, err := loadTemplate(cfg, ctx.Status)
+	if err != nil {
+		return fmt.Errorf("notify: load template: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, ctx); err != nil {
+		return fmt.Errorf("notify: render template: %w", err)
+	}
+
+	sender, err := shoutrrr.CreateSender(cfg.URLs...)
+	if err != nil {
+		return fmt.Errorf("notify: create sender: %w", err)
+	}
+
+	for _, err := range sender.Send(body.String(), nil) {
+		if err != nil {
+			return fmt.Errorf("notify: send: %w", err)
+		}
+	}
+	return nil
+}
+
+func loadTemplate(cfg Config, status string) (*template.Template, error) {
+	if cfg.TemplatePath != "" {
+		data, err := os.ReadFile(cfg.TemplatePath)
+		if err != nil {
+			return nil, err
+		}
+		return template.New("notification").Parse(string(data))
+	}
+
+	name := "templates/success.tmpl"
+	if status == "error" {
+		name = "templates/failure.tmpl"
+	}
+
+	data, err := defaultTemplates.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return template.New("notification").Parse(string(data))
+}
@@ -0,0 +1,84 @@
+// Package azure implements a storage backend backed by an Azure Blob
+// Storage container.
+package azure
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/maestroi/snapshot-service/internal/storage"
+)
+
+// Config holds the connection details for an Azure Blob Storage container.
+type Config struct {
+	AccountName   string `json:"account_name"`
+	AccountKey    string `json:"account_key"`
+	ContainerName string `json:"container_name"`
+}
+
+// Backend is a storage.StorageBackend backed by an Azure Blob container.
+type Backend struct {
+	cfg    Config
+	client *azblob.Client
+}
+
+func New(cfg Config) (*Backend, error) {
+	cred, err := azblob.NewSharedKeyCredential(cfg.AccountName, cfg.AccountKey)
+	if err != nil {
+		return nil, fmt.Errorf("azure: build credential: %w", err)
+	}
+
+	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", cfg.AccountName)
+	client, err := azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("azure: create client: %w", err)
+	}
+
+	return &Backend{cfg: cfg, client: client}, nil
+}
+
+func (b *Backend) Name() string {
+	return "azure"
+}
+
+func (b *Backend) Upload(ctx context.Context, key string, r io.Reader) error {
+	_, err := b.client.UploadStream(ctx, b.cfg.ContainerName, key, r, nil)
+	return err
+}
+
+func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := b.client.DownloadStream(ctx, b.cfg.ContainerName, key, nil)
+	if err != nil {
+		return nil, fmt.Errorf("azure: download %s: %w", key, err)
+	}
+	return resp.Body, nil
+}
+
+func (b *Backend) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+	var objects []storage.Object
+
+	pager := b.client.NewListBlobsFlatPager(b.cfg.ContainerName, &azblob.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("azure: list blobs: %w", err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			objects = append(objects, storage.Object{
+				Key:          *item.Name,
+				Size:         *item.Properties.ContentLength,
+				LastModified: *item.Properties.LastModified,
+			})
+		}
+	}
+	return objects, nil
+}
+
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.DeleteBlob(ctx, b.cfg.ContainerName, key, nil)
+	return err
+}
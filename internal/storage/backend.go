@@ -0,0 +1,30 @@
+// Package storage defines the abstract target a snapshot archive (and its
+// metadata) is uploaded to and pruned from. Concrete implementations live
+// under internal/storage/<name>, one sub-package per destination, so a
+// single backup run can fan out to several of them at once.
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Object describes a single entry returned by StorageBackend.List.
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// StorageBackend abstracts a snapshot destination.
+type StorageBackend interface {
+	// Name identifies the backend in logs and notifications, e.g. "s3".
+	Name() string
+	Upload(ctx context.Context, key string, r io.Reader) error
+	// Download streams the object back, for restore. The caller must close
+	// the returned ReadCloser.
+	Download(ctx context.Context, key string) (io.ReadCloser, error)
+	List(ctx context.Context, prefix string) ([]Object, error)
+	Delete(ctx context.Context, key string) error
+}
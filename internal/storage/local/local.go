@@ -0,0 +1,102 @@
+// Package local implements a storage backend that writes snapshots to a
+// directory on the local filesystem, useful for testing or when the
+// destination is already mounted (NFS, bind-mounted volume, etc).
+package local
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/maestroi/snapshot-service/internal/storage"
+)
+
+// Config points the backend at a base directory all keys are written under.
+type Config struct {
+	BasePath string `json:"base_path"`
+}
+
+// Backend is a storage.StorageBackend backed by the local filesystem.
+type Backend struct {
+	cfg Config
+}
+
+func New(cfg Config) (*Backend, error) {
+	if err := os.MkdirAll(cfg.BasePath, 0755); err != nil {
+		return nil, err
+	}
+	return &Backend{cfg: cfg}, nil
+}
+
+func (b *Backend) Name() string {
+	return "local"
+}
+
+func (b *Backend) path(key string) string {
+	return filepath.Join(b.cfg.BasePath, filepath.FromSlash(key))
+}
+
+func (b *Backend) Upload(ctx context.Context, key string, r io.Reader) error {
+	dst := b.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(b.path(key))
+}
+
+func (b *Backend) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+	root := b.path(prefix)
+
+	var objects []storage.Object
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(b.cfg.BasePath, path)
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, storage.Object{
+			Key:          filepath.ToSlash(rel),
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	err := os.Remove(b.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// fileCredentialsTTL bounds how long a "file" source's credentials are
+// reused before Retrieve is called again to pick up a rotated file.
+const fileCredentialsTTL = 15 * time.Minute
+
+// VaultConfig locates an access_key/secret_key pair in a HashiCorp Vault KV
+// v2 secret, authenticating via AppRole. RoleIDFile and SecretIDFile point
+// at files (as mounted by a Vault Agent sidecar or init container) rather
+// than taking the values directly, so they never end up in the config file
+// or process environment.
+type VaultConfig struct {
+	Address      string `json:"address"`
+	MountPath    string `json:"mount_path"`
+	SecretPath   string `json:"secret_path"`
+	Role         string `json:"role"`
+	RoleIDFile   string `json:"role_id_file"`
+	SecretIDFile string `json:"secret_id_file"`
+}
+
+// credentialsProvider builds the *credentials.Credentials every S3 request
+// should acquire its access/secret key pair through, selected by
+// cfg.CredentialsSource. The returned value wraps a credentials.Provider
+// that the AWS SDK re-invokes whenever the previous value has expired, so
+// short-lived tokens (STS, Vault leases) keep working across the backend's
+// lifetime instead of only at startup.
+func credentialsProvider(cfg Config) (*credentials.Credentials, error) {
+	switch cfg.CredentialsSource {
+	case "", "static":
+		return credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretKey, ""), nil
+	case "env":
+		return credentials.NewEnvCredentials(), nil
+	case "file":
+		if cfg.CredentialsFile == "" {
+			return nil, fmt.Errorf("s3: credentials_source %q requires credentials_file", cfg.CredentialsSource)
+		}
+		return credentials.NewCredentials(&fileProvider{path: cfg.CredentialsFile}), nil
+	case "aws_default_chain":
+		sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+		if err != nil {
+			return nil, fmt.Errorf("s3: build default credential chain: %w", err)
+		}
+		return sess.Config.Credentials, nil
+	case "vault":
+		if cfg.Vault == nil {
+			return nil, fmt.Errorf("s3: credentials_source %q requires a vault config", cfg.CredentialsSource)
+		}
+		return credentials.NewCredentials(newVaultProvider(*cfg.Vault)), nil
+	default:
+		return nil, fmt.Errorf("s3: unknown credentials_source %q", cfg.CredentialsSource)
+	}
+}
+
+// fileProvider reads a mounted secret file holding
+// {"access_key": "...", "secret_key": "...", "session_token": "..."}, as
+// written by tools like the AWS Secrets Manager / Vault CSI driver. It
+// re-reads the file once the secret has expired rather than caching it for
+// the backend's lifetime, so a rotated file is picked up without a
+// restart.
+type fileProvider struct {
+	path string
+	credentials.Expiry
+}
+
+func (p *fileProvider) Retrieve() (credentials.Value, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("s3: read credentials_file: %w", err)
+	}
+
+	var secret struct {
+		AccessKey    string `json:"access_key"`
+		SecretKey    string `json:"secret_key"`
+		SessionToken string `json:"session_token"`
+	}
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return credentials.Value{}, fmt.Errorf("s3: parse credentials_file: %w", err)
+	}
+
+	p.SetExpiration(time.Now().Add(fileCredentialsTTL), 0)
+	return credentials.Value{
+		AccessKeyID:     secret.AccessKey,
+		SecretAccessKey: secret.SecretKey,
+		SessionToken:    secret.SessionToken,
+		ProviderName:    "S3CredentialsFile",
+	}, nil
+}
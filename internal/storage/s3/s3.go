@@ -0,0 +1,146 @@
+// Package s3 implements the S3 (and S3-compatible) storage backend.
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awss3 "github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/maestroi/snapshot-service/internal/storage"
+)
+
+// Config holds the connection details for an S3-compatible bucket.
+type Config struct {
+	BucketName string `json:"bucket_name"`
+	AccessKey  string `json:"access_key"`
+	SecretKey  string `json:"secret_key"`
+	Endpoint   string `json:"endpoint"`
+	Region     string `json:"region"`
+
+	// CredentialsSource selects where AccessKey/SecretKey are read from:
+	// "" or "static" (use AccessKey/SecretKey as-is), "env" (the process
+	// environment), "file" (CredentialsFile, re-read on expiry), "vault"
+	// (Vault, configured by the Vault field), or "aws_default_chain" (the
+	// AWS SDK's own chain, covering IRSA/IMDS and ~/.aws/credentials).
+	CredentialsSource string       `json:"credentials_source"`
+	CredentialsFile   string       `json:"credentials_file"`
+	Vault             *VaultConfig `json:"vault,omitempty"`
+
+	// HTTPProxy, if set, is used only for requests this backend's S3
+	// client makes; it does not set HTTP_PROXY for the rest of the
+	// process.
+	HTTPProxy string `json:"http_proxy"`
+}
+
+// Backend is a storage.StorageBackend backed by an S3-compatible bucket.
+type Backend struct {
+	cfg     Config
+	session *session.Session
+}
+
+func New(cfg Config) (*Backend, error) {
+	creds, err := credentialsProvider(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+
+	httpClient, err := proxyScopedHTTPClient(cfg.HTTPProxy)
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String(cfg.Region),
+		Credentials:      creds,
+		Endpoint:         aws.String(cfg.Endpoint),
+		S3ForcePathStyle: aws.Bool(true),
+		HTTPClient:       httpClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3: create session: %w", err)
+	}
+
+	return &Backend{cfg: cfg, session: sess}, nil
+}
+
+// proxyScopedHTTPClient returns an *http.Client that routes through proxy
+// (a URL like "http://proxy.internal:3128"), or http.DefaultClient when
+// proxy is empty, so setting it never affects requests made by the rest of
+// the process.
+func proxyScopedHTTPClient(proxy string) (*http.Client, error) {
+	if proxy == "" {
+		return http.DefaultClient, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("parse http_proxy: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return &http.Client{Transport: transport}, nil
+}
+
+func (b *Backend) Name() string {
+	return "s3"
+}
+
+func (b *Backend) Upload(ctx context.Context, key string, r io.Reader) error {
+	uploader := s3manager.NewUploader(b.session)
+	_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(b.cfg.BucketName),
+		Key:    aws.String(key),
+		Body:   r,
+	})
+	return err
+}
+
+func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	svc := awss3.New(b.session)
+	resp, err := svc.GetObjectWithContext(ctx, &awss3.GetObjectInput{
+		Bucket: aws.String(b.cfg.BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (b *Backend) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+	svc := awss3.New(b.session)
+
+	resp, err := svc.ListObjectsV2WithContext(ctx, &awss3.ListObjectsV2Input{
+		Bucket: aws.String(b.cfg.BucketName),
+		Prefix: aws.String(prefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]storage.Object, 0, len(resp.Contents))
+	for _, item := range resp.Contents {
+		objects = append(objects, storage.Object{
+			Key:          aws.StringValue(item.Key),
+			Size:         aws.Int64Value(item.Size),
+			LastModified: aws.TimeValue(item.LastModified),
+		})
+	}
+	return objects, nil
+}
+
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	svc := awss3.New(b.session)
+	_, err := svc.DeleteObjectWithContext(ctx, &awss3.DeleteObjectInput{
+		Bucket: aws.String(b.cfg.BucketName),
+		Key:    aws.String(key),
+	})
+	return err
+}
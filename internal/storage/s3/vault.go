@@ -0,0 +1,79 @@
+package s3
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+// vaultProvider logs into Vault with AppRole and reads an access_key /
+// secret_key pair out of a KV v2 secret on every Retrieve, so it tracks
+// key rotation and expiring leases without the backend being restarted.
+type vaultProvider struct {
+	cfg VaultConfig
+	credentials.Expiry
+}
+
+func newVaultProvider(cfg VaultConfig) *vaultProvider {
+	return &vaultProvider{cfg: cfg}
+}
+
+func (p *vaultProvider) Retrieve() (credentials.Value, error) {
+	client, err := vaultapi.NewClient(&vaultapi.Config{Address: p.cfg.Address})
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("s3: create vault client: %w", err)
+	}
+
+	roleID, err := readTrimmed(p.cfg.RoleIDFile)
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("s3: read vault role_id_file: %w", err)
+	}
+	secretID, err := readTrimmed(p.cfg.SecretIDFile)
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("s3: read vault secret_id_file: %w", err)
+	}
+
+	login, err := client.Logical().Write("auth/approle/login", map[string]interface{}{
+		"role_id":   roleID,
+		"secret_id": secretID,
+	})
+	if err != nil || login == nil || login.Auth == nil {
+		return credentials.Value{}, fmt.Errorf("s3: vault approle login for role %q: %w", p.cfg.Role, err)
+	}
+	client.SetToken(login.Auth.ClientToken)
+
+	secret, err := client.Logical().Read(fmt.Sprintf("%s/data/%s", strings.Trim(p.cfg.MountPath, "/"), strings.Trim(p.cfg.SecretPath, "/")))
+	if err != nil || secret == nil {
+		return credentials.Value{}, fmt.Errorf("s3: read vault secret %s/%s: %w", p.cfg.MountPath, p.cfg.SecretPath, err)
+	}
+	data, _ := secret.Data["data"].(map[string]interface{})
+	accessKey, _ := data["access_key"].(string)
+	secretKey, _ := data["secret_key"].(string)
+	if accessKey == "" || secretKey == "" {
+		return credentials.Value{}, fmt.Errorf("s3: vault secret %s/%s missing access_key/secret_key", p.cfg.MountPath, p.cfg.SecretPath)
+	}
+
+	leaseDuration := time.Duration(login.Auth.LeaseDuration) * time.Second
+	if leaseDuration <= 0 {
+		leaseDuration = 15 * time.Minute
+	}
+	p.SetExpiration(time.Now().Add(leaseDuration), 0)
+
+	return credentials.Value{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+		ProviderName:    "S3VaultKVv2",
+	}, nil
+}
+
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
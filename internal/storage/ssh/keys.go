@@ -0,0 +1,15 @@
+package ssh
+
+import (
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func loadPrivateKey(path string) (ssh.Signer, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.ParsePrivateKey(key)
+}
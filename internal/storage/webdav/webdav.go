@@ -0,0 +1,82 @@
+// Package webdav implements a storage backend that uploads snapshots to a
+// WebDAV share (e.g. Nextcloud, Synology NAS).
+package webdav
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/maestroi/snapshot-service/internal/storage"
+	"github.com/studio-b12/gowebdav"
+)
+
+// Config holds the connection details for a WebDAV share.
+type Config struct {
+	URL      string `json:"url"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	BasePath string `json:"base_path"`
+}
+
+// Backend is a storage.StorageBackend backed by a WebDAV share.
+type Backend struct {
+	cfg    Config
+	client *gowebdav.Client
+}
+
+func New(cfg Config) (*Backend, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webdav: url is required")
+	}
+	return &Backend{cfg: cfg, client: gowebdav.NewClient(cfg.URL, cfg.User, cfg.Password)}, nil
+}
+
+func (b *Backend) Name() string {
+	return "webdav"
+}
+
+func (b *Backend) remotePath(key string) string {
+	return path.Join(b.cfg.BasePath, key)
+}
+
+func (b *Backend) Upload(ctx context.Context, key string, r io.Reader) error {
+	dst := b.remotePath(key)
+	if err := b.client.MkdirAll(path.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("webdav: mkdir %s: %w", path.Dir(dst), err)
+	}
+	return b.client.WriteStream(dst, r, 0644)
+}
+
+func (b *Backend) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	r, err := b.client.ReadStream(b.remotePath(key))
+	if err != nil {
+		return nil, fmt.Errorf("webdav: read %s: %w", key, err)
+	}
+	return r, nil
+}
+
+func (b *Backend) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+	entries, err := b.client.ReadDir(b.remotePath(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("webdav: readdir %s: %w", prefix, err)
+	}
+
+	objects := make([]storage.Object, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		objects = append(objects, storage.Object{
+			Key:          path.Join(prefix, entry.Name()),
+			Size:         entry.Size(),
+			LastModified: entry.ModTime(),
+		})
+	}
+	return objects, nil
+}
+
+func (b *Backend) Delete(ctx context.Context, key string) error {
+	return b.client.Remove(b.remotePath(key))
+}